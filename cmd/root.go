@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/highercomve/s3-migrate/lib"
 	"github.com/spf13/cobra"
@@ -44,31 +45,71 @@ func init() {
 	rootCmd.PersistentFlags().String("cpuprofile", "", "CPU profiling")
 
 	// Source bucket flags
-	rootCmd.Flags().StringP("source-key", "k", "", "Source s3 ACCESS_KEY")
-	rootCmd.Flags().StringP("source-secret", "s", "", "Source s3 SECRET")
-	rootCmd.Flags().StringP("source-region", "r", "", "Source s3 REGION")
-	rootCmd.Flags().StringP("source-bucket", "b", "", "Source s3 BUCKET")
-	rootCmd.Flags().StringP("source-endpoint", "e", "", "Source s3 ENDPOINT")
+	rootCmd.PersistentFlags().String("source-driver", "s3", "Source store driver (s3, cos, oss, azure, gcs)")
+	rootCmd.PersistentFlags().StringP("source-key", "k", "", "Source s3/oss ACCESS_KEY")
+	rootCmd.PersistentFlags().StringP("source-secret", "s", "", "Source s3/oss SECRET")
+	rootCmd.PersistentFlags().StringP("source-region", "r", "", "Source s3 REGION")
+	rootCmd.PersistentFlags().StringP("source-bucket", "b", "", "Source s3/oss/gcs BUCKET")
+	rootCmd.PersistentFlags().StringP("source-endpoint", "e", "", "Source s3/oss ENDPOINT")
+	rootCmd.PersistentFlags().String("source-bucket-url", "", "Source COS bucket URL")
+	rootCmd.PersistentFlags().String("source-secret-id", "", "Source COS SecretID")
+	rootCmd.PersistentFlags().String("source-secret-key", "", "Source COS SecretKey")
+	rootCmd.PersistentFlags().String("source-account", "", "Source Azure storage account")
+	rootCmd.PersistentFlags().String("source-container", "", "Source Azure container")
+	rootCmd.PersistentFlags().String("source-sas-token", "", "Source Azure SAS token")
 
 	// Destination bucket flags
-	rootCmd.Flags().StringP("dest-key", "K", "", "Destination s3 ACCESS_KEY")
-	rootCmd.Flags().StringP("dest-secret", "S", "", "Destination s3 SECRET")
-	rootCmd.Flags().StringP("dest-region", "R", "", "Destination s3 REGION")
-	rootCmd.Flags().StringP("dest-bucket", "B", "", "Destination s3 BUCKET")
-	rootCmd.Flags().StringP("dest-endpoint", "E", "", "Destination s3 ENDPOINT")
+	rootCmd.PersistentFlags().String("dest-driver", "s3", "Destination store driver (s3, cos, oss, azure, gcs)")
+	rootCmd.PersistentFlags().StringP("dest-key", "K", "", "Destination s3/oss ACCESS_KEY")
+	rootCmd.PersistentFlags().StringP("dest-secret", "S", "", "Destination s3/oss SECRET")
+	rootCmd.PersistentFlags().StringP("dest-region", "R", "", "Destination s3 REGION")
+	rootCmd.PersistentFlags().StringP("dest-bucket", "B", "", "Destination s3/oss/gcs BUCKET")
+	rootCmd.PersistentFlags().StringP("dest-endpoint", "E", "", "Destination s3/oss ENDPOINT")
+	rootCmd.PersistentFlags().String("dest-bucket-url", "", "Destination COS bucket URL")
+	rootCmd.PersistentFlags().String("dest-secret-id", "", "Destination COS SecretID")
+	rootCmd.PersistentFlags().String("dest-secret-key", "", "Destination COS SecretKey")
+	rootCmd.PersistentFlags().String("dest-account", "", "Destination Azure storage account")
+	rootCmd.PersistentFlags().String("dest-container", "", "Destination Azure container")
+	rootCmd.PersistentFlags().String("dest-sas-token", "", "Destination Azure SAS token")
 
 	// Database flags
-	rootCmd.Flags().StringP("database", "d", "", "database name")
-	rootCmd.Flags().StringP("collection", "c", "", "database collection")
-	rootCmd.Flags().StringP("connection", "m", "", "database connection url")
-	rootCmd.Flags().StringP("filter", "f", `{"sizeint":{"$gt": 0}}`, "database filter")
+	rootCmd.PersistentFlags().StringP("database", "d", "", "database name")
+	rootCmd.PersistentFlags().StringP("collection", "c", "", "database collection")
+	rootCmd.PersistentFlags().StringP("connection", "m", "", "database connection url")
+	rootCmd.PersistentFlags().StringP("filter", "f", `{"sizeint":{"$gt": 0}}`, "database filter")
 
 	// Performance flags
-	rootCmd.Flags().Int64P("limit", "l", 100, "Request limit")
-	rootCmd.Flags().Int64("ratelimit", 0, "rate limit per second to search for objects in s3")
-	rootCmd.Flags().Int64("concurrency", 0, "concurrency level")
-
-	viper.BindPFlags(rootCmd.Flags())
+	rootCmd.PersistentFlags().Int64P("limit", "l", 100, "Request limit")
+	rootCmd.PersistentFlags().Int64("ratelimit", 0, "rate limit per second to search for objects in s3")
+	rootCmd.PersistentFlags().Int64("concurrency", 0, "concurrency level")
+
+	// Copy flags
+	rootCmd.PersistentFlags().Int64("part-size", 64*1024*1024, "part size in bytes for multipart copy of large objects")
+	rootCmd.PersistentFlags().Int("copy-parallelism", 4, "number of parts to copy concurrently for multipart copy")
+	rootCmd.PersistentFlags().Bool("server-side-copy", true, "use the S3 server-side copy API when source and destination share the same endpoint and credentials")
+
+	// Resume/checkpoint flags
+	rootCmd.PersistentFlags().String("state-file", "", "path to a BoltDB file used to persist migration checkpoint state")
+	rootCmd.PersistentFlags().String("state-collection", "", "MongoDB collection used to persist migration checkpoint state, in the same database as --database")
+	rootCmd.PersistentFlags().Bool("resume", false, "skip objects already completed according to checkpoint state")
+	rootCmd.PersistentFlags().Bool("retry-failed", false, "re-attempt objects checkpointed as errored")
+	rootCmd.PersistentFlags().Int("max-attempts", 3, "maximum copy attempts per object before giving up")
+
+	// Verification flags
+	rootCmd.PersistentFlags().String("verify", "none", "integrity check to run after copy: none, size, etag or sha256")
+
+	// Watch flags
+	rootCmd.PersistentFlags().String("resume-token-file", "", "path to a file used to persist the change stream resume token for `watch`")
+	rootCmd.PersistentFlags().String("start-at-operation-time", "", "RFC3339 timestamp to start tailing changes from when no resume token is available")
+	rootCmd.PersistentFlags().Duration("poll-interval", 30*time.Second, "polling interval for `watch` when change streams are unavailable")
+
+	// Observability flags
+	rootCmd.PersistentFlags().String("metrics-addr", "", "address to serve Prometheus metrics on (e.g. :9090); disabled when empty")
+	rootCmd.PersistentFlags().String("log-format", "text", "log output format: text or json")
+	rootCmd.PersistentFlags().String("log-level", "info", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().String("report-format", "text", "final migration report format: text or json")
+
+	viper.BindPFlags(rootCmd.PersistentFlags())
 }
 
 func initConfig() {
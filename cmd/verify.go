@@ -0,0 +1,21 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package cmd
+
+import (
+	"github.com/highercomve/s3-migrate/lib"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify migrated objects against their source without copying",
+	Long:  `Walk the database filter and check each object's integrity against its source bucket, without performing any copy.`,
+	RunE:  lib.VerifyStorage,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
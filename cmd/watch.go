@@ -0,0 +1,21 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package cmd
+
+import (
+	"github.com/highercomve/s3-migrate/lib"
+	"github.com/spf13/cobra"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Continuously migrate objects as they are inserted or updated",
+	Long:  `Tail the source collection for inserted or updated documents and migrate each as it arrives, instead of walking a fixed snapshot. Useful for cutover windows where writes keep landing against the source.`,
+	RunE:  lib.WatchStorage,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// logger is the package-wide structured logger, reconfigured by
+// configureLogging from --log-format and --log-level. It defaults to a text
+// handler at info level so callers that skip configureLogging (none in
+// practice) still get reasonable output.
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// configureLogging rebuilds the package logger from --log-format
+// (text or json) and --log-level (debug, info, warn or error).
+func configureLogging(format, level string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level: %s (want debug, info, warn or error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format: %s (want text or json)", format)
+	}
+
+	logger = slog.New(handler)
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics updated from the migrateObjects worker goroutines, exposed on
+// --metrics-addr so a long-running migration can be watched from
+// Grafana/Alertmanager alongside the rest of the storage infra.
+var (
+	objectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3migrate_objects_total",
+		Help: "Objects processed, labeled by outcome (copied, skipped or error).",
+	}, []string{"status"})
+
+	bytesCopiedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "s3migrate_bytes_copied_total",
+		Help: "Total bytes successfully copied to the destination bucket.",
+	})
+
+	copyDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "s3migrate_copy_duration_seconds",
+		Help:    "Time spent copying and verifying a single object, including retries.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	inFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "s3migrate_in_flight",
+		Help: "Objects currently being copied.",
+	})
+
+	errorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "s3migrate_errors_total",
+		Help: "Errors encountered, labeled by error code.",
+	}, []string{"code"})
+
+	ratelimitWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "s3migrate_ratelimit_wait_seconds",
+		Help:    "Time a worker spent waiting on --ratelimit before processing an object.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+// serveMetrics starts the Prometheus /metrics endpoint on addr in the
+// background. It's a no-op when addr is empty, so metrics stay opt-in.
+func serveMetrics(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		logger.Info("serving Prometheus metrics", "addr", addr, "path", "/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Error("metrics server error", "err", err)
+		}
+	}()
+}
+
+// errorCode classifies err for the errors_total{code=...} label.
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var verr *verificationError
+	if errors.As(err, &verr) {
+		return "verification_failed"
+	}
+
+	if resp := minio.ToErrorResponse(err); resp.Code != "" {
+		return resp.Code
+	}
+
+	return "unknown"
+}
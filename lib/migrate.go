@@ -6,12 +6,15 @@ package lib
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"os"
+	"os/signal"
 	"runtime"
 	"runtime/pprof"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -23,8 +26,8 @@ import (
 )
 
 type S3MigrationParams struct {
-	Source      *S3ConnParams
-	Destination *S3ConnParams
+	Source      *StoreParams
+	Destination *StoreParams
 	Database    string
 	Collection  *mongo.Collection
 	Connection  string
@@ -33,6 +36,14 @@ type S3MigrationParams struct {
 	RateLimit   int64
 	DryRun      bool
 	Concurrency int64
+
+	StateFile       string
+	StateCollection string
+	Resume          bool
+	RetryFailed     bool
+	MaxAttempts     int
+
+	Verify VerifyLevel
 }
 
 type Object struct {
@@ -56,25 +67,50 @@ type MigrationReport struct {
 	Skipped              int64         `json:"skipped"`
 	AlreadyInDestination int64         `json:"already_in_destination"`
 	Errors               int64         `json:"errors"`
+	Verified             int64         `json:"verified"`
+	VerificationFailed   int64         `json:"verification_failed"`
 	StartTime            time.Time     `json:"start_time"`
 	EndTime              time.Time     `json:"end_time"`
 	Duration             time.Duration `json:"duration"`
 }
 
-func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
+// buildMigrationParams reads flags and builds everything a run needs except
+// the document count: migration params and a rate limiter. It does not
+// require the source filter to match any existing documents, so WatchStorage
+// uses it directly instead of prepareMigration.
+func buildMigrationParams(ctx context.Context) (*S3MigrationParams, *rate.Limiter, error) {
+	if err := configureLogging(viper.GetString("log-format"), viper.GetString("log-level")); err != nil {
+		return nil, nil, err
+	}
+	serveMetrics(viper.GetString("metrics-addr"))
+
 	// Source bucket params
+	sourceDriver := viper.GetString("source-driver")
 	sourceKey := viper.GetString("source-key")
 	sourceSecret := viper.GetString("source-secret")
 	sourceRegion := viper.GetString("source-region")
 	sourceBucket := viper.GetString("source-bucket")
 	sourceEndpoint := viper.GetString("source-endpoint")
+	sourceBucketURL := viper.GetString("source-bucket-url")
+	sourceSecretID := viper.GetString("source-secret-id")
+	sourceSecretKey := viper.GetString("source-secret-key")
+	sourceAccount := viper.GetString("source-account")
+	sourceContainer := viper.GetString("source-container")
+	sourceSASToken := viper.GetString("source-sas-token")
 
 	// Destination bucket params
+	destDriver := viper.GetString("dest-driver")
 	destKey := viper.GetString("dest-key")
 	destSecret := viper.GetString("dest-secret")
 	destRegion := viper.GetString("dest-region")
 	destBucket := viper.GetString("dest-bucket")
 	destEndpoint := viper.GetString("dest-endpoint")
+	destBucketURL := viper.GetString("dest-bucket-url")
+	destSecretID := viper.GetString("dest-secret-id")
+	destSecretKey := viper.GetString("dest-secret-key")
+	destAccount := viper.GetString("dest-account")
+	destContainer := viper.GetString("dest-container")
+	destSASToken := viper.GetString("dest-sas-token")
 
 	// Database params
 	database := viper.GetString("database")
@@ -88,11 +124,26 @@ func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
 	if concurrency == 0 {
 		concurrency = int64(runtime.NumCPU())
 	}
+	partSize := viper.GetInt64("part-size")
+	copyParallelism := viper.GetInt("copy-parallelism")
+	serverSideCopy := viper.GetBool("server-side-copy")
+
+	// Resume/checkpoint params
+	stateFile := viper.GetString("state-file")
+	stateCollection := viper.GetString("state-collection")
+	resume := viper.GetBool("resume")
+	retryFailed := viper.GetBool("retry-failed")
+	maxAttempts := viper.GetInt("max-attempts")
+
+	verify, err := ParseVerifyLevel(viper.GetString("verify"))
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Print configuration
 	fmt.Printf("\nMigration Configuration:\n")
-	fmt.Printf("Source: %s (%s, %s)\n", sourceBucket, sourceRegion, sourceEndpoint)
-	fmt.Printf("Destination: %s (%s, %s)\n", destBucket, destRegion, destEndpoint)
+	fmt.Printf("Source: %s driver %s (%s, %s)\n", sourceBucket, sourceDriver, sourceRegion, sourceEndpoint)
+	fmt.Printf("Destination: %s driver %s (%s, %s)\n", destBucket, destDriver, destRegion, destEndpoint)
 	fmt.Printf("Database: %s\n", database)
 	fmt.Printf("Collection: %s\n", collectionName)
 	fmt.Printf("Connection: %s\n", connection)
@@ -105,58 +156,78 @@ func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
 		fmt.Printf("Dry Run: Enabled\n")
 	}
 	fmt.Printf("Concurrency Level: %d\n", concurrency)
+	fmt.Printf("Part Size: %d bytes\n", partSize)
+	fmt.Printf("Copy Parallelism: %d\n", copyParallelism)
+	fmt.Printf("Server-side Copy: %t\n", serverSideCopy)
+	fmt.Printf("Verify: %s\n", verify)
 
 	// CPU profiling
 	cpuprofile := viper.GetString("cpuprofile")
 	if cpuprofile != "" {
 		f, err := os.Create(cpuprofile)
 		if err != nil {
-			log.Fatal(err)
+			logger.Error(err.Error())
+			os.Exit(1)
 		}
 		pprof.StartCPUProfile(f)
-		defer pprof.StopCPUProfile()
 	}
 
 	// Parse filter
 	filter := bson.M{}
 	if err := json.Unmarshal([]byte(filterString), &filter); err != nil {
-		log.Printf("Error parsing filter configuration: %v", err)
-		return err
+		logger.Error("error parsing filter configuration", "err", err)
+		return nil, nil, err
 	}
-	log.Printf("Successfully parsed filter configuration: %v", filter)
-
-	// Create context
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	logger.Info("successfully parsed filter configuration", "filter", filter)
 
 	// Connect to MongoDB
-	// Log the attempt to connect to MongoDB
-	log.Println("Attempting to connect to MongoDB...")
+	logger.Info("Attempting to connect to MongoDB...")
 	storage, err := NewDbConnection(ctx, connection)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	// Log successful connection
-	log.Println("Successfully connected to MongoDB.")
+	logger.Info("Successfully connected to MongoDB.")
 
 	db := storage.GetDatabase(database)
 	collection := db.Collection(collectionName)
 
-	// Create S3 clients
-	sourceParams := &S3ConnParams{
-		Key:      sourceKey,
-		Secret:   sourceSecret,
-		Region:   sourceRegion,
-		Bucket:   sourceBucket,
-		Endpoint: sourceEndpoint,
+	// Create object store params
+	sourceParams := &StoreParams{
+		Driver:    sourceDriver,
+		Key:       sourceKey,
+		Secret:    sourceSecret,
+		Region:    sourceRegion,
+		Bucket:    sourceBucket,
+		Endpoint:  sourceEndpoint,
+		BucketURL: sourceBucketURL,
+		SecretID:  sourceSecretID,
+		SecretKey: sourceSecretKey,
+		Account:   sourceAccount,
+		Container: sourceContainer,
+		SASToken:  sourceSASToken,
+
+		PartSize:        partSize,
+		CopyParallelism: copyParallelism,
+		ServerSideCopy:  serverSideCopy,
 	}
 
-	destParams := &S3ConnParams{
-		Key:      destKey,
-		Secret:   destSecret,
-		Region:   destRegion,
-		Bucket:   destBucket,
-		Endpoint: destEndpoint,
+	destParams := &StoreParams{
+		Driver:    destDriver,
+		Key:       destKey,
+		Secret:    destSecret,
+		Region:    destRegion,
+		Bucket:    destBucket,
+		Endpoint:  destEndpoint,
+		BucketURL: destBucketURL,
+		SecretID:  destSecretID,
+		SecretKey: destSecretKey,
+		Account:   destAccount,
+		Container: destContainer,
+		SASToken:  destSASToken,
+
+		PartSize:        partSize,
+		CopyParallelism: copyParallelism,
+		ServerSideCopy:  serverSideCopy,
 	}
 
 	// Create rate limiter if specified
@@ -165,20 +236,6 @@ func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
 		limiter = rate.NewLimiter(rate.Limit(ratelimit), 1)
 	}
 
-	// Get total count of documents
-	count, err := collection.CountDocuments(ctx, filter)
-	if err != nil {
-		return err
-	}
-
-	if count <= 0 {
-		fmt.Println("Zero objects found to migrate. Exiting.")
-		return nil
-	}
-
-	fmt.Printf("Found %d objects to migrate\n\n", count)
-
-	// Create migration params
 	migrationParams := &S3MigrationParams{
 		Source:      sourceParams,
 		Destination: destParams,
@@ -190,31 +247,105 @@ func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
 		RateLimit:   ratelimit,
 		DryRun:      dryRun,
 		Concurrency: concurrency,
+
+		StateFile:       stateFile,
+		StateCollection: stateCollection,
+		Resume:          resume,
+		RetryFailed:     retryFailed,
+		MaxAttempts:     maxAttempts,
+
+		Verify: verify,
 	}
 
-	// Start migration
-	return migrateObjects(ctx, migrationParams, count, limiter)
+	return migrationParams, limiter, nil
+}
+
+// prepareMigration builds on buildMigrationParams by also counting the
+// documents matching the filter, for callers (MigrateStorage, VerifyStorage)
+// that have nothing to do when that count is zero. A nil params with a nil
+// error means there was nothing to do.
+func prepareMigration(ctx context.Context) (*S3MigrationParams, int64, *rate.Limiter, error) {
+	params, limiter, err := buildMigrationParams(ctx)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	count, err := params.Collection.CountDocuments(ctx, params.Filter)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if count <= 0 {
+		fmt.Println("Zero objects found to migrate. Exiting.")
+		return nil, 0, nil, nil
+	}
+
+	fmt.Printf("Found %d objects to migrate\n\n", count)
+
+	return params, count, limiter, nil
+}
+
+func MigrateStorage(cmd *cobra.Command, args []string) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cpuprofile := viper.GetString("cpuprofile")
+	if cpuprofile != "" {
+		defer pprof.StopCPUProfile()
+	}
+
+	params, count, limiter, err := prepareMigration(ctx)
+	if err != nil || params == nil {
+		return err
+	}
+
+	return migrateObjects(ctx, params, count, limiter)
 }
 
 func migrateObjects(ctx context.Context, params *S3MigrationParams, totalCount int64, limiter *rate.Limiter) error {
 	startTime := time.Now()
 
-	sourceClient, err := NewS3Connect(ctx, params.Source)
+	sourceClient, err := NewObjectStore(ctx, params.Source)
+	if err != nil {
+		return err
+	}
+
+	destClient, err := NewObjectStore(ctx, params.Destination)
 	if err != nil {
 		return err
 	}
 
-	destClient, err := NewS3Connect(ctx, params.Destination)
+	stateStore, err := newStateStore(params)
 	if err != nil {
 		return err
 	}
+	defer stateStore.Close(ctx)
+
+	checkpoints, err := stateStore.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading checkpoint state: %v", err)
+	}
+	logger.Info("loaded existing checkpoints", "count", len(checkpoints))
+
+	maxAttempts := params.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	// Stop feeding new work once interrupted, but let in-flight copies and
+	// their checkpoints finish so the report reflects a clean partial run.
+	// Only the enqueue loop watches sigCtx; workers keep using ctx.
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Create worker pool
 	var wg sync.WaitGroup
 	objectChan := make(chan Object, params.Concurrency)
 
-	// Statistics tracking
-	var copiedCount, skippedCount, alreadyExistsCount, errorCount int64
+	// Statistics tracking. These are incremented concurrently from every
+	// worker goroutine below, so they need atomics rather than plain int64s.
+	var copiedCount, skippedCount, alreadyExistsCount, errorCount atomic.Int64
+	var verifiedCount, verificationFailedCount atomic.Int64
 
 	// Start workers
 	for i := int64(0); i < params.Concurrency; i++ {
@@ -224,57 +355,132 @@ func migrateObjects(ctx context.Context, params *S3MigrationParams, totalCount i
 			for doc := range objectChan {
 				// Apply rate limiting if configured
 				if limiter != nil {
-					if err := limiter.Wait(ctx); err != nil {
-						log.Printf("Rate limiter error: %v", err)
-						errorCount++
+					waitStart := time.Now()
+					err := limiter.Wait(ctx)
+					ratelimitWaitSeconds.Observe(time.Since(waitStart).Seconds())
+					if err != nil {
+						logger.Info("rate limiter error", "err", err)
+						errorCount.Add(1)
+						errorsTotal.WithLabelValues(errorCode(err)).Inc()
 						continue
 					}
 				}
 
 				// Get object SHA from document
 				storageID := doc.StorageID
+				attempt := checkpoints[storageID].Attempt
+
 				// Check if object exists in source bucket
-				exists, err := sourceClient.ObjectExist(ctx, storageID)
+				exists, err := sourceClient.Exists(ctx, storageID)
 				if err != nil {
-					log.Printf("Error checking object %s: %v", storageID, err)
-					errorCount++
+					logger.Error("error checking object", "storage_id", storageID, "err", err)
+					errorCount.Add(1)
+					errorsTotal.WithLabelValues(errorCode(err)).Inc()
+					saveCheckpoint(ctx, stateStore, doc, StatusError, attempt+1)
 					continue
 				}
 
 				if !exists {
-					log.Printf("Object %s not found in source bucket", storageID)
-					skippedCount++
+					logger.Info("object not found in source bucket", "storage_id", storageID)
+					skippedCount.Add(1)
+					saveCheckpoint(ctx, stateStore, doc, StatusSkipped, attempt+1)
 					continue
 				}
 
-				// Check if object exists in destination bucket with the same SHA
-				destExists, err := destClient.ObjectExist(ctx, storageID)
+				// Check if object exists in destination bucket
+				destExists, err := destClient.Exists(ctx, storageID)
 				if err != nil {
-					log.Printf("Error checking object %s in destination bucket: %v", storageID, err)
-					errorCount++
+					logger.Error("error checking object in destination bucket", "storage_id", storageID, "err", err)
+					errorCount.Add(1)
+					errorsTotal.WithLabelValues(errorCode(err)).Inc()
+					saveCheckpoint(ctx, stateStore, doc, StatusError, attempt+1)
 					continue
 				}
 
 				if destExists {
-					log.Printf("Object %s already exists in destination bucket with matching SHA. Skipping copy.", storageID)
-					alreadyExistsCount++
-					continue
+					verified, verr := verifyObject(ctx, sourceClient, destClient, doc, params.Verify, params.Source.PartSize)
+					if verr != nil {
+						logger.Error("error verifying object", "storage_id", storageID, "err", verr)
+						errorCount.Add(1)
+						errorsTotal.WithLabelValues(errorCode(verr)).Inc()
+						saveCheckpoint(ctx, stateStore, doc, StatusError, attempt+1)
+						continue
+					}
+
+					if verified {
+						logger.Info("object already exists in destination bucket, skipping copy", "storage_id", storageID)
+						alreadyExistsCount.Add(1)
+						if params.Verify != VerifyNone {
+							verifiedCount.Add(1)
+						}
+						saveCheckpoint(ctx, stateStore, doc, StatusSkipped, attempt+1)
+						continue
+					}
+
+					verificationFailedCount.Add(1)
+
+					// Re-copy below with the remaining attempt budget instead of
+					// giving up on the first verification mismatch. The
+					// remainingAttempts <= 0 clamp further down grants this path
+					// the same one extra --retry-failed attempt it grants an
+					// object missing from the destination.
+					logger.Error("object exists in destination but failed verification, re-copying", "storage_id", storageID, "attempt", attempt)
 				}
 
 				if params.DryRun {
-					log.Printf("Dry Run: Would copy object %s from source to destination", storageID)
-					copiedCount++
+					logger.Info("dry run: would copy object from source to destination", "storage_id", storageID)
+					copiedCount.Add(1)
 					continue
 				}
 
-				// Copy object from source to destination
-				if err := destClient.CopyObject(ctx, sourceClient, storageID, params.DryRun); err != nil {
-					log.Printf("Error copying object %s: %v", storageID, err)
-					errorCount++
+				// Copy object from source to destination, retrying transient
+				// S3 errors and verification failures with backoff
+				remainingAttempts := maxAttempts - attempt
+				if remainingAttempts <= 0 {
+					// --retry-failed resumes checkpoints whose Attempt already
+					// reached maxAttempts (shouldSkipCheckpoint never skips
+					// StatusError for it), so grant exactly the one extra
+					// attempt it asked for instead of relying on withRetry's
+					// own <=0 clamp to do that implicitly.
+					remainingAttempts = 1
+				}
+				inFlight.Inc()
+				copyStart := time.Now()
+				copyAttempts, err := withRetry(ctx, remainingAttempts, func() error {
+					if err := destClient.Copy(ctx, sourceClient, storageID, params.DryRun); err != nil {
+						return err
+					}
+
+					verified, verr := verifyObject(ctx, sourceClient, destClient, doc, params.Verify, params.Source.PartSize)
+					if verr != nil {
+						return verr
+					}
+					if !verified {
+						return &verificationError{msg: fmt.Sprintf("verification failed for object %s", storageID)}
+					}
+
+					return nil
+				})
+				copyDurationSeconds.Observe(time.Since(copyStart).Seconds())
+				inFlight.Dec()
+
+				if err != nil {
+					logger.Error("error copying object", "storage_id", storageID, "attempts", copyAttempts, "err", err)
+					errorCount.Add(1)
+					errorsTotal.WithLabelValues(errorCode(err)).Inc()
+					var verr *verificationError
+					if errors.As(err, &verr) {
+						verificationFailedCount.Add(1)
+					}
+					saveCheckpoint(ctx, stateStore, doc, StatusError, attempt+copyAttempts)
 					continue
 				}
 
-				copiedCount++
+				copiedCount.Add(1)
+				if params.Verify != VerifyNone {
+					verifiedCount.Add(1)
+				}
+				saveCheckpoint(ctx, stateStore, doc, StatusCopied, attempt+copyAttempts)
 			}
 		}()
 	}
@@ -287,12 +493,28 @@ func migrateObjects(ctx context.Context, params *S3MigrationParams, totalCount i
 	defer cursor.Close(ctx)
 
 	for cursor.Next(ctx) {
+		if sigCtx.Err() != nil {
+			logger.Info("Migration interrupted, no longer enqueuing new objects")
+			break
+		}
+
 		var doc Object
 		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("Error decoding document: %v", err)
-			errorCount++
+			logger.Error("error decoding document", "err", err)
+			errorCount.Add(1)
+			errorsTotal.WithLabelValues(errorCode(err)).Inc()
 			continue
 		}
+
+		if cp := checkpoints[doc.StorageID]; shouldSkipCheckpoint(cp, params) {
+			if cp.Status == StatusError {
+				errorCount.Add(1)
+			} else {
+				alreadyExistsCount.Add(1)
+			}
+			continue
+		}
+
 		objectChan <- doc
 	}
 
@@ -305,21 +527,36 @@ func migrateObjects(ctx context.Context, params *S3MigrationParams, totalCount i
 	// Final summary report
 	report := MigrationReport{
 		TotalObjects:         totalCount,
-		Copied:               copiedCount,
-		Skipped:              skippedCount,
-		AlreadyInDestination: alreadyExistsCount,
-		Errors:               errorCount,
+		Copied:               copiedCount.Load(),
+		Skipped:              skippedCount.Load(),
+		AlreadyInDestination: alreadyExistsCount.Load(),
+		Errors:               errorCount.Load(),
+		Verified:             verifiedCount.Load(),
+		VerificationFailed:   verificationFailedCount.Load(),
 		StartTime:            startTime,
 		EndTime:              endTime,
 		Duration:             duration,
 	}
 
+	if viper.GetString("report-format") == "json" {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("error marshaling migration report: %v", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
 	fmt.Println("\nMigration Summary Report:")
 	fmt.Printf("Total Objects: %d\n", report.TotalObjects)
 	fmt.Printf("Copied: %d\n", report.Copied)
 	fmt.Printf("Skipped (not found in source): %d\n", report.Skipped)
 	fmt.Printf("Already in Destination: %d\n", report.AlreadyInDestination)
 	fmt.Printf("Errors: %d\n", report.Errors)
+	if report.Verified > 0 || report.VerificationFailed > 0 {
+		fmt.Printf("Verified: %d\n", report.Verified)
+		fmt.Printf("Verification Failed: %d\n", report.VerificationFailed)
+	}
 	fmt.Printf("Start Time: %s\n", report.StartTime)
 	fmt.Printf("End Time: %s\n", report.EndTime)
 	fmt.Printf("Duration: ")
@@ -336,3 +573,66 @@ func migrateObjects(ctx context.Context, params *S3MigrationParams, totalCount i
 	fmt.Println("\nMigration completed!")
 	return nil
 }
+
+// newStateStore builds the checkpoint store selected by --state-file or
+// --state-collection, or a no-op store if neither was set.
+func newStateStore(params *S3MigrationParams) (StateStore, error) {
+	switch {
+	case params.StateFile != "":
+		return NewBoltStateStore(params.StateFile)
+	case params.StateCollection != "":
+		db := params.Collection.Database()
+		return NewMongoStateStore(db.Collection(params.StateCollection)), nil
+	default:
+		return &noopStateStore{}, nil
+	}
+}
+
+// shouldSkipCheckpoint decides whether an object already has checkpoint
+// state good enough to skip re-enqueuing it.
+func shouldSkipCheckpoint(cp Checkpoint, params *S3MigrationParams) bool {
+	if cp.StorageID == "" {
+		return false
+	}
+
+	maxAttempts := params.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	switch cp.Status {
+	case StatusCopied:
+		return params.Resume
+	case StatusError:
+		if params.RetryFailed {
+			return false
+		}
+		return params.Resume && cp.Attempt >= maxAttempts
+	default:
+		return false
+	}
+}
+
+// saveCheckpoint persists the outcome of migrating doc, logging but not
+// failing the migration if the state store write itself errors. It also
+// records the outcome for s3migrate_objects_total and, on a successful copy,
+// s3migrate_bytes_copied_total.
+func saveCheckpoint(ctx context.Context, store StateStore, doc Object, status CheckpointStatus, attempt int) {
+	cp := Checkpoint{
+		StorageID: doc.StorageID,
+		Status:    status,
+		Sha256:    doc.Sha,
+		Bytes:     doc.SizeInt,
+		Attempt:   attempt,
+		Timestamp: time.Now(),
+	}
+
+	objectsTotal.WithLabelValues(string(status)).Inc()
+	if status == StatusCopied {
+		bytesCopiedTotal.Add(float64(doc.SizeInt))
+	}
+
+	if err := store.Save(ctx, cp); err != nil {
+		logger.Error("error saving checkpoint", "storage_id", doc.StorageID, "err", err)
+	}
+}
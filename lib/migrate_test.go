@@ -0,0 +1,66 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import "testing"
+
+func TestShouldSkipCheckpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		cp     Checkpoint
+		params *S3MigrationParams
+		want   bool
+	}{
+		{
+			name:   "no checkpoint",
+			cp:     Checkpoint{},
+			params: &S3MigrationParams{Resume: true},
+			want:   false,
+		},
+		{
+			name:   "copied without resume",
+			cp:     Checkpoint{StorageID: "a", Status: StatusCopied},
+			params: &S3MigrationParams{},
+			want:   false,
+		},
+		{
+			name:   "copied with resume",
+			cp:     Checkpoint{StorageID: "a", Status: StatusCopied},
+			params: &S3MigrationParams{Resume: true},
+			want:   true,
+		},
+		{
+			name:   "error under max attempts with resume",
+			cp:     Checkpoint{StorageID: "a", Status: StatusError, Attempt: 1},
+			params: &S3MigrationParams{Resume: true, MaxAttempts: 3},
+			want:   false,
+		},
+		{
+			name:   "error at max attempts with resume",
+			cp:     Checkpoint{StorageID: "a", Status: StatusError, Attempt: 3},
+			params: &S3MigrationParams{Resume: true, MaxAttempts: 3},
+			want:   true,
+		},
+		{
+			name:   "error at max attempts, retry-failed overrides resume",
+			cp:     Checkpoint{StorageID: "a", Status: StatusError, Attempt: 3},
+			params: &S3MigrationParams{Resume: true, RetryFailed: true, MaxAttempts: 3},
+			want:   false,
+		},
+		{
+			name:   "skipped status never skips",
+			cp:     Checkpoint{StorageID: "a", Status: StatusSkipped},
+			params: &S3MigrationParams{Resume: true},
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldSkipCheckpoint(tt.cp, tt.params); got != tt.want {
+				t.Errorf("shouldSkipCheckpoint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// transientErrorCodes are minio-go ErrorResponse.Code values worth retrying;
+// everything else (NoSuchKey, AccessDenied, ...) is permanent.
+var transientErrorCodes = map[string]bool{
+	"SlowDown":           true,
+	"RequestTimeout":     true,
+	"InternalError":      true,
+	"ServiceUnavailable": true,
+}
+
+// isTransientS3Error reports whether err is a retryable S3 error: a known
+// transient error code, or any 5xx response.
+func isTransientS3Error(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	resp := minio.ToErrorResponse(err)
+	if transientErrorCodes[resp.Code] {
+		return true
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+// isRetryableError reports whether err is worth a retry: a transient S3
+// error, or a failed integrity verification.
+func isRetryableError(err error) bool {
+	var verr *verificationError
+	if errors.As(err, &verr) {
+		return true
+	}
+
+	return isTransientS3Error(err)
+}
+
+// withRetry calls fn up to maxAttempts times, retrying only on errors
+// isRetryableError accepts, with exponential backoff and jitter. It returns
+// the attempt count it stopped at and the last error, if any.
+func withRetry(ctx context.Context, maxAttempts int, fn func() error) (attempt int, err error) {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	for attempt = 1; attempt <= maxAttempts; attempt++ {
+		err = fn()
+		if err == nil {
+			return attempt, nil
+		}
+
+		if attempt == maxAttempts || !isRetryableError(err) {
+			return attempt, err
+		}
+
+		backoff := time.Duration(1<<uint(attempt-1)) * 200 * time.Millisecond
+		jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+		select {
+		case <-time.After(backoff + jitter):
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		}
+	}
+
+	return attempt, err
+}
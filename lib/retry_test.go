@@ -0,0 +1,111 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestIsTransientS3Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"known transient code", minio.ErrorResponse{Code: "SlowDown"}, true},
+		{"5xx status", minio.ErrorResponse{Code: "SomethingElse", StatusCode: http.StatusBadGateway}, true},
+		{"permanent code", minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientS3Error(tt.err); got != tt.want {
+				t.Errorf("isTransientS3Error(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	if !isRetryableError(&verificationError{msg: "mismatch"}) {
+		t.Error("verificationError should be retryable")
+	}
+
+	if isRetryableError(errors.New("boom")) {
+		t.Error("plain error should not be retryable")
+	}
+
+	if !isRetryableError(minio.ErrorResponse{Code: "SlowDown"}) {
+		t.Error("transient S3 error should be retryable")
+	}
+}
+
+func TestWithRetrySucceedsWithoutRetrying(t *testing.T) {
+	calls := 0
+	attempts, err := withRetry(context.Background(), 3, func() error {
+		calls++
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 1 || calls != 1 {
+		t.Errorf("attempts = %d, calls = %d, want 1 and 1", attempts, calls)
+	}
+}
+
+func TestWithRetryStopsOnPermanentError(t *testing.T) {
+	calls := 0
+	permanent := errors.New("permanent")
+	attempts, err := withRetry(context.Background(), 3, func() error {
+		calls++
+		return permanent
+	})
+
+	if err != permanent {
+		t.Fatalf("err = %v, want %v", err, permanent)
+	}
+	if attempts != 1 || calls != 1 {
+		t.Errorf("attempts = %d, calls = %d, want 1 and 1", attempts, calls)
+	}
+}
+
+func TestWithRetryExhaustsMaxAttemptsOnTransientError(t *testing.T) {
+	calls := 0
+	transient := minio.ErrorResponse{Code: "SlowDown"}
+	attempts, err := withRetry(context.Background(), 3, func() error {
+		calls++
+		return transient
+	})
+
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries")
+	}
+	if attempts != 3 || calls != 3 {
+		t.Errorf("attempts = %d, calls = %d, want 3 and 3", attempts, calls)
+	}
+}
+
+func TestWithRetryClampsNonPositiveMaxAttempts(t *testing.T) {
+	calls := 0
+	attempts, err := withRetry(context.Background(), 0, func() error {
+		calls++
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 1 || calls != 1 {
+		t.Errorf("attempts = %d, calls = %d, want 1 and 1", attempts, calls)
+	}
+}
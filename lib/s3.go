@@ -7,151 +7,267 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
+	"os"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
-	"github.com/schollz/progressbar/v3"
 )
 
 var endpoint string = "s3.amazonaws.com"
 
-type S3ConnParams struct {
-	Key      string
-	Secret   string
-	Region   string
-	Bucket   string
-	Endpoint string
-}
+const (
+	defaultPartSize        = 64 * 1024 * 1024 // 64 MiB
+	defaultCopyParallelism = 4
+)
 
+// S3Client is the minio/S3-compatible ObjectStore driver.
 type S3Client struct {
 	client *minio.Client
+	core   *minio.Core
 	bucket string
+
+	// endpoint and key identify the account this client talks to, used to
+	// decide whether a Copy can take the server-side fast path.
+	endpoint string
+	key      string
+
+	partSize        int64
+	copyParallelism int
+	serverSideCopy  bool
 }
 
-func NewS3Connect(ctxP context.Context, params *S3ConnParams) (client *S3Client, err error) {
-	s3Client := &S3Client{bucket: params.Bucket}
+// NewS3Connect connects to an S3 or minio-compatible endpoint.
+func NewS3Connect(ctxP context.Context, params *StoreParams) (client *S3Client, err error) {
+	ep := endpoint
 	if params.Endpoint != "" {
-		endpoint = params.Endpoint
+		ep = params.Endpoint
 	}
 
-	s3Client.client, err = minio.New(endpoint, &minio.Options{
+	s3Client := &S3Client{
+		bucket:          params.Bucket,
+		endpoint:        ep,
+		key:             params.Key,
+		partSize:        params.PartSize,
+		copyParallelism: params.CopyParallelism,
+		serverSideCopy:  params.ServerSideCopy,
+	}
+
+	opts := &minio.Options{
 		Creds:  credentials.NewStaticV4(params.Key, params.Secret, ""),
 		Region: params.Region,
 		Secure: true,
-	})
+	}
+
+	s3Client.client, err = minio.New(ep, opts)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	s3Client.core, err = minio.NewCore(ep, opts)
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error(err.Error())
+		os.Exit(1)
 	}
 
 	return s3Client, err
 }
 
-func (s *S3Client) ObjectExist(ctx context.Context, id string) (exist bool, err error) {
-	exist = false
+// Stat returns metadata for the object with the given id.
+func (s *S3Client) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	info, err := s.client.StatObject(ctx, s.bucket, id, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		StorageClass: info.StorageClass,
+		UserMetadata: info.UserMetadata,
+	}, nil
+}
 
+// Get returns a stream for the object with the given id.
+func (s *S3Client) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	return s.client.GetObject(ctx, s.bucket, id, minio.GetObjectOptions{})
+}
+
+// Put uploads reader as the object with the given id.
+func (s *S3Client) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	_, err := s.client.PutObject(ctx, s.bucket, id, reader, size, minio.PutObjectOptions{
+		ContentType:  info.ContentType,
+		StorageClass: info.StorageClass,
+		UserMetadata: info.UserMetadata,
+	})
+	return err
+}
+
+// Exists reports whether the object with the given id exists in the bucket.
+func (s *S3Client) Exists(ctx context.Context, id string) (exist bool, err error) {
 	_, err = s.client.StatObject(ctx, s.bucket, id, minio.StatObjectOptions{})
 	if err != nil && strings.Contains(err.Error(), "The specified key does not exist") {
 		return false, nil
 	}
 	if err != nil {
-		return
+		return false, err
 	}
 
-	exist = true
+	return true, nil
+}
 
-	return exist, err
+// List returns the ids of objects under prefix.
+func (s *S3Client) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		ids = append(ids, obj.Key)
+	}
+
+	return ids, nil
 }
 
-// CopyObject copies an object from source to destination bucket
-func (s *S3Client) CopyObjectOld(ctx context.Context, source *S3Client, objectSHA string, dryRun bool) error {
+// Copy copies an object from source. When server-side copy is enabled and
+// source and destination are on the same S3 account it takes the server-side
+// copy fast path; otherwise objects larger than the part size go through a
+// parallel multipart copy. Anything else streams through genericCopy with a
+// progress bar.
+func (s *S3Client) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
 	if dryRun {
-		fmt.Println("Dry run enabled: no action taken")
-		return nil
+		return genericCopy(ctx, s, source, id, dryRun)
+	}
+
+	sourceS3, isS3 := source.(*S3Client)
+	if !isS3 {
+		return genericCopy(ctx, s, source, id, dryRun)
 	}
 
-	// Get object info for content type
-	objectInfo, err := source.client.StatObject(ctx, source.bucket, objectSHA, minio.StatObjectOptions{})
+	if s.serverSideCopy && sourceS3.endpoint == s.endpoint && sourceS3.key == s.key {
+		return s.copyServerSide(ctx, sourceS3, id)
+	}
+
+	info, err := source.Stat(ctx, id)
 	if err != nil {
 		return fmt.Errorf("error getting object info: %v", err)
 	}
 
-	// Get object from source as a stream
-	object, err := source.client.GetObject(ctx, source.bucket, objectSHA, minio.GetObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("error getting object from source: %v", err)
+	partSize := s.partSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
 	}
-	defer object.Close()
 
-	// Put object in destination as a stream
-	_, err = s.client.PutObject(ctx, s.bucket, objectSHA, object, objectInfo.Size, minio.PutObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("error putting object in destination: %v", err)
+	if info.Size > partSize {
+		return s.multipartCopy(ctx, sourceS3, id, info, partSize)
+	}
+
+	return genericCopy(ctx, s, source, id, dryRun)
+}
+
+// copyServerSide uses the S3 CopyObject API so the object data never passes
+// through this process.
+func (s *S3Client) copyServerSide(ctx context.Context, source *S3Client, id string) error {
+	src := minio.CopySrcOptions{Bucket: source.bucket, Object: id}
+	dst := minio.CopyDestOptions{Bucket: s.bucket, Object: id}
+
+	if _, err := s.client.CopyObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("error copying object %s server-side: %v", id, err)
 	}
 
 	return nil
 }
 
-// CopyObject copies an object with progress bar
-func (s *S3Client) CopyObject(ctx context.Context, source *S3Client, objectSHA string, dryRun bool) error {
-	// Get object info for size
-	objectInfo, err := source.client.StatObject(ctx, source.bucket, objectSHA, minio.StatObjectOptions{})
+// multipartCopy splits a large cross-endpoint object into parts, fetches
+// each part range from source concurrently, and uploads it to the
+// destination as a multipart upload. Every part is attempted; if any fails,
+// the upload is aborted and the first error received is returned.
+func (s *S3Client) multipartCopy(ctx context.Context, source *S3Client, id string, info ObjectInfo, partSize int64) error {
+	parallelism := s.copyParallelism
+	if parallelism <= 0 {
+		parallelism = defaultCopyParallelism
+	}
+
+	uploadID, err := s.core.NewMultipartUpload(ctx, s.bucket, id, minio.PutObjectOptions{
+		ContentType:  info.ContentType,
+		StorageClass: info.StorageClass,
+		UserMetadata: info.UserMetadata,
+	})
 	if err != nil {
-		return fmt.Errorf("error getting object info: %v", err)
+		return fmt.Errorf("error initiating multipart upload for %s: %v", id, err)
 	}
 
-	// Create progress bar
-	bar := progressbar.DefaultBytes(
-		objectInfo.Size,
-		fmt.Sprintf("Copying %s", objectSHA),
-	)
+	numParts := int((info.Size + partSize - 1) / partSize)
+	parts := make([]minio.CompletePart, numParts)
 
-	if dryRun {
-		// For dry run, simulate the progress bar filling up
-		for i := int64(0); i <= objectInfo.Size; i += objectInfo.Size / 100 {
-			bar.Set64(i)
-			time.Sleep(10 * time.Millisecond)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	errCh := make(chan error, numParts)
+
+	for i := 0; i < numParts; i++ {
+		start := int64(i) * partSize
+		end := start + partSize - 1
+		if end >= info.Size {
+			end = info.Size - 1
 		}
-		bar.Finish()
-		fmt.Println(" (Dry run: no actual copy performed)")
-		return nil
-	}
 
-	// Get object from source as a stream
-	object, err := source.client.GetObject(ctx, source.bucket, objectSHA, minio.GetObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("error getting object from source: %v", err)
-	}
-	defer object.Close()
-
-	// Create a pipe to stream data and update progress bar
-	pr, pw := io.Pipe()
-	go func() {
-		defer pw.Close()
-		buf := make([]byte, 32*1024) // 32KB buffer
-		for {
-			n, err := object.Read(buf)
-			if n > 0 {
-				pw.Write(buf[:n])
-				bar.Add(n)
-			}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(partNumber int, start, end int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			part, err := s.copyPart(ctx, source, id, uploadID, partNumber, start, end)
 			if err != nil {
-				if err != io.EOF {
-					log.Printf("error reading object: %v", err)
-				}
-				break
+				errCh <- err
+				return
 			}
+
+			parts[partNumber-1] = part
+		}(i+1, start, end)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	if err := <-errCh; err != nil {
+		if abortErr := s.core.AbortMultipartUpload(ctx, s.bucket, id, uploadID); abortErr != nil {
+			logger.Error("error aborting multipart upload", "id", id, "err", abortErr)
 		}
-	}()
+		return fmt.Errorf("error copying part for %s: %v", id, err)
+	}
 
-	// Put object in destination as a stream
-	_, err = s.client.PutObject(ctx, s.bucket, objectSHA, pr, objectInfo.Size, minio.PutObjectOptions{})
-	if err != nil {
-		return fmt.Errorf("error putting object in destination: %v", err)
+	if _, err := s.core.CompleteMultipartUpload(ctx, s.bucket, id, uploadID, parts, minio.PutObjectOptions{}); err != nil {
+		return fmt.Errorf("error completing multipart upload for %s: %v", id, err)
 	}
 
-	bar.Finish()
 	return nil
 }
+
+// copyPart fetches the [start, end] byte range from source and uploads it as
+// a single part of the in-progress destination multipart upload.
+func (s *S3Client) copyPart(ctx context.Context, source *S3Client, id, uploadID string, partNumber int, start, end int64) (minio.CompletePart, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return minio.CompletePart{}, err
+	}
+
+	reader, err := source.client.GetObject(ctx, source.bucket, id, opts)
+	if err != nil {
+		return minio.CompletePart{}, fmt.Errorf("error getting part %d: %v", partNumber, err)
+	}
+	defer reader.Close()
+
+	part, err := s.core.PutObjectPart(ctx, s.bucket, id, uploadID, partNumber, reader, end-start+1, minio.PutObjectPartOptions{})
+	if err != nil {
+		return minio.CompletePart{}, fmt.Errorf("error uploading part %d: %v", partNumber, err)
+	}
+
+	return minio.CompletePart{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+	}, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CheckpointStatus records the outcome of the last attempt to migrate an
+// object, so a resumed run knows what still needs doing.
+type CheckpointStatus string
+
+const (
+	StatusCopied  CheckpointStatus = "copied"
+	StatusSkipped CheckpointStatus = "skipped"
+	StatusError   CheckpointStatus = "error"
+)
+
+// Checkpoint is the persisted state for a single migrated object.
+type Checkpoint struct {
+	StorageID string           `json:"storage_id" bson:"_id"`
+	Status    CheckpointStatus `json:"status" bson:"status"`
+	Sha256    string           `json:"sha256" bson:"sha256"`
+	Bytes     int64            `json:"bytes" bson:"bytes"`
+	Attempt   int              `json:"attempt" bson:"attempt"`
+	Timestamp time.Time        `json:"timestamp" bson:"timestamp"`
+}
+
+// StateStore persists migration checkpoints so an interrupted run can be
+// resumed without re-copying objects that already finished.
+type StateStore interface {
+	Load(ctx context.Context) (map[string]Checkpoint, error)
+	Save(ctx context.Context, cp Checkpoint) error
+	Close(ctx context.Context) error
+}
+
+var checkpointBucket = []byte("checkpoints")
+
+// boltStateStore persists checkpoints to a local BoltDB file, selected with
+// --state-file.
+type boltStateStore struct {
+	db *bbolt.DB
+}
+
+// NewBoltStateStore opens (creating if necessary) a BoltDB checkpoint file.
+func NewBoltStateStore(path string) (StateStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening state file %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(checkpointBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error initializing state file %s: %v", path, err)
+	}
+
+	return &boltStateStore{db: db}, nil
+}
+
+func (s *boltStateStore) Load(ctx context.Context) (map[string]Checkpoint, error) {
+	checkpoints := map[string]Checkpoint{}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointBucket).ForEach(func(k, v []byte) error {
+			var cp Checkpoint
+			if err := json.Unmarshal(v, &cp); err != nil {
+				return err
+			}
+			checkpoints[cp.StorageID] = cp
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+func (s *boltStateStore) Save(ctx context.Context, cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointBucket).Put([]byte(cp.StorageID), data)
+	})
+}
+
+func (s *boltStateStore) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+// mongoStateStore persists checkpoints to a MongoDB collection, selected
+// with --state-collection.
+type mongoStateStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStateStore stores checkpoints in the given collection.
+func NewMongoStateStore(collection *mongo.Collection) StateStore {
+	return &mongoStateStore{collection: collection}
+}
+
+func (s *mongoStateStore) Load(ctx context.Context) (map[string]Checkpoint, error) {
+	checkpoints := map[string]Checkpoint{}
+
+	cursor, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var cp Checkpoint
+		if err := cursor.Decode(&cp); err != nil {
+			return nil, err
+		}
+		checkpoints[cp.StorageID] = cp
+	}
+
+	return checkpoints, cursor.Err()
+}
+
+func (s *mongoStateStore) Save(ctx context.Context, cp Checkpoint) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := s.collection.ReplaceOne(ctx, bson.M{"_id": cp.StorageID}, cp, opts)
+	return err
+}
+
+func (s *mongoStateStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// noopStateStore is used when neither --state-file nor --state-collection
+// is set, so resume/retry support stays opt-in.
+type noopStateStore struct{}
+
+func (s *noopStateStore) Load(ctx context.Context) (map[string]Checkpoint, error) {
+	return map[string]Checkpoint{}, nil
+}
+
+func (s *noopStateStore) Save(ctx context.Context, cp Checkpoint) error {
+	return nil
+}
+
+func (s *noopStateStore) Close(ctx context.Context) error {
+	return nil
+}
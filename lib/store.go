@@ -0,0 +1,139 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// ObjectInfo carries the subset of object metadata every backend can report,
+// used to preserve content type and size across a Copy.
+type ObjectInfo struct {
+	Size         int64
+	ETag         string
+	ContentType  string
+	StorageClass string
+	UserMetadata map[string]string
+}
+
+// ObjectStore is implemented by every supported object-storage backend so
+// migrateObjects can move objects between any pair of them without
+// driver-specific code.
+type ObjectStore interface {
+	Stat(ctx context.Context, id string) (ObjectInfo, error)
+	Get(ctx context.Context, id string) (io.ReadCloser, error)
+	Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error
+	Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error
+	Exists(ctx context.Context, id string) (bool, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// StoreParams configures a connection to any ObjectStore driver selected by
+// Driver. Only the fields relevant to that driver need to be set.
+type StoreParams struct {
+	Driver string
+
+	// S3 / minio-compatible
+	Key      string
+	Secret   string
+	Region   string
+	Bucket   string
+	Endpoint string
+
+	// Tencent COS
+	BucketURL string
+	SecretID  string
+	SecretKey string
+
+	// Aliyun OSS reuses Key, Secret, Endpoint and Bucket above
+
+	// Azure Blob
+	Account   string
+	Container string
+	SASToken  string
+
+	// Copy tuning, used by drivers that support a server-side or
+	// multipart copy fast path (currently the S3 driver)
+	PartSize        int64
+	CopyParallelism int
+	ServerSideCopy  bool
+}
+
+// NewObjectStore connects to the backend selected by params.Driver. An empty
+// Driver defaults to the S3/minio-compatible driver.
+func NewObjectStore(ctx context.Context, params *StoreParams) (ObjectStore, error) {
+	switch params.Driver {
+	case "", "s3":
+		return NewS3Connect(ctx, params)
+	case "cos":
+		return NewCOSStore(ctx, params)
+	case "oss":
+		return NewOSSStore(ctx, params)
+	case "azure":
+		return NewAzureStore(ctx, params)
+	case "gcs":
+		return NewGCSStore(ctx, params)
+	default:
+		return nil, fmt.Errorf("unknown store driver: %s", params.Driver)
+	}
+}
+
+// genericCopy streams an object from source to dest through Get/Put with a
+// progress bar. Drivers without a cheaper server-side copy path use this for
+// their Copy implementation.
+func genericCopy(ctx context.Context, dest ObjectStore, source ObjectStore, id string, dryRun bool) error {
+	info, err := source.Stat(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting object info: %v", err)
+	}
+
+	bar := progressbar.DefaultBytes(info.Size, fmt.Sprintf("Copying %s", id))
+
+	if dryRun {
+		for i := int64(0); i <= info.Size; i += info.Size/100 + 1 {
+			bar.Set64(i)
+			time.Sleep(10 * time.Millisecond)
+		}
+		bar.Finish()
+		fmt.Println(" (Dry run: no actual copy performed)")
+		return nil
+	}
+
+	object, err := source.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error getting object from source: %v", err)
+	}
+	defer object.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		buf := make([]byte, 32*1024) // 32KB buffer
+		for {
+			n, err := object.Read(buf)
+			if n > 0 {
+				pw.Write(buf[:n])
+				bar.Add(n)
+			}
+			if err != nil {
+				if err != io.EOF {
+					logger.Error("error reading object", "err", err)
+				}
+				break
+			}
+		}
+	}()
+
+	if err := dest.Put(ctx, id, pr, info.Size, info); err != nil {
+		return fmt.Errorf("error putting object in destination: %v", err)
+	}
+
+	bar.Finish()
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+// AzureStore is the Azure Blob Storage ObjectStore driver.
+type AzureStore struct {
+	container *azblob.ContainerClient
+}
+
+// NewAzureStore connects to an Azure Blob container using a SAS token.
+func NewAzureStore(ctx context.Context, params *StoreParams) (*AzureStore, error) {
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s?%s", params.Account, params.Container, params.SASToken)
+
+	container, err := azblob.NewContainerClientWithNoCredential(serviceURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Azure container client: %v", err)
+	}
+
+	return &AzureStore{container: container}, nil
+}
+
+// Stat returns metadata for the object with the given id.
+func (s *AzureStore) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	props, err := s.container.NewBlobClient(id).GetProperties(ctx, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	info := ObjectInfo{}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	if props.ETag != nil {
+		info.ETag = string(*props.ETag)
+	}
+	if props.ContentType != nil {
+		info.ContentType = *props.ContentType
+	}
+
+	return info, nil
+}
+
+// Get returns a stream for the object with the given id.
+func (s *AzureStore) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := s.container.NewBlobClient(id).Download(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body(nil), nil
+}
+
+// Put uploads reader as the object with the given id.
+func (s *AzureStore) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	opts := &azblob.UploadStreamOptions{Metadata: info.UserMetadata}
+	if info.ContentType != "" {
+		opts.HTTPHeaders = &azblob.BlobHTTPHeaders{BlobContentType: &info.ContentType}
+	}
+
+	// UploadStream reads forward only, so it doesn't need the end-seek that
+	// BlockBlobClient.Upload requires to compute Content-Length up front.
+	_, err := s.container.NewBlockBlobClient(id).UploadStream(ctx, reader, opts)
+	return err
+}
+
+// Exists reports whether the object with the given id exists in the container.
+func (s *AzureStore) Exists(ctx context.Context, id string) (bool, error) {
+	_, err := s.container.NewBlobClient(id).GetProperties(ctx, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "BlobNotFound") {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List returns the ids of objects under prefix.
+func (s *AzureStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	pager := s.container.ListBlobsFlat(&azblob.ContainerListBlobsFlatOptions{Prefix: &prefix})
+	for pager.NextPage(ctx) {
+		for _, blob := range pager.PageResponse().Segment.BlobItems {
+			ids = append(ids, *blob.Name)
+		}
+	}
+	if err := pager.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Copy copies an object from source with a progress bar.
+func (s *AzureStore) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
+	return genericCopy(ctx, s, source, id, dryRun)
+}
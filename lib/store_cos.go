@@ -0,0 +1,103 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	cos "github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// COSStore is the Tencent Cloud Object Storage ObjectStore driver.
+type COSStore struct {
+	client *cos.Client
+}
+
+// NewCOSStore connects to a Tencent COS bucket.
+func NewCOSStore(ctx context.Context, params *StoreParams) (*COSStore, error) {
+	bucketURL, err := url.Parse(params.BucketURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing COS bucket URL: %v", err)
+	}
+
+	client := cos.NewClient(&cos.BaseURL{BucketURL: bucketURL}, &http.Client{
+		Transport: &cos.AuthorizationTransport{
+			SecretID:  params.SecretID,
+			SecretKey: params.SecretKey,
+		},
+	})
+
+	return &COSStore{client: client}, nil
+}
+
+// Stat returns metadata for the object with the given id.
+func (s *COSStore) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	resp, err := s.client.Object.Head(ctx, id, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	return ObjectInfo{
+		Size:        resp.ContentLength,
+		ETag:        resp.Header.Get("ETag"),
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// Get returns a stream for the object with the given id.
+func (s *COSStore) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := s.client.Object.Get(ctx, id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// Put uploads reader as the object with the given id.
+func (s *COSStore) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	_, err := s.client.Object.Put(ctx, id, reader, &cos.ObjectPutOptions{
+		ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
+			ContentType:   info.ContentType,
+			ContentLength: int(size),
+		},
+	})
+	return err
+}
+
+// Exists reports whether the object with the given id exists in the bucket.
+func (s *COSStore) Exists(ctx context.Context, id string) (bool, error) {
+	return s.client.Object.IsExist(ctx, id)
+}
+
+// List returns the ids of objects under prefix.
+func (s *COSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	marker := ""
+	for {
+		result, _, err := s.client.Bucket.Get(ctx, &cos.BucketGetOptions{Prefix: prefix, Marker: marker})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			ids = append(ids, c.Key)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	return ids, nil
+}
+
+// Copy copies an object from source with a progress bar.
+func (s *COSStore) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
+	return genericCopy(ctx, s, source, id, dryRun)
+}
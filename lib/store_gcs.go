@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStore is the Google Cloud Storage ObjectStore driver.
+type GCSStore struct {
+	bucket *storage.BucketHandle
+}
+
+// NewGCSStore connects to a Google Cloud Storage bucket.
+func NewGCSStore(ctx context.Context, params *StoreParams) (*GCSStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCS client: %v", err)
+	}
+
+	return &GCSStore{bucket: client.Bucket(params.Bucket)}, nil
+}
+
+// Stat returns metadata for the object with the given id.
+func (s *GCSStore) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	attrs, err := s.bucket.Object(id).Attrs(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	return ObjectInfo{
+		Size:         attrs.Size,
+		ETag:         attrs.Etag,
+		ContentType:  attrs.ContentType,
+		StorageClass: attrs.StorageClass,
+	}, nil
+}
+
+// Get returns a stream for the object with the given id.
+func (s *GCSStore) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	return s.bucket.Object(id).NewReader(ctx)
+}
+
+// Put uploads reader as the object with the given id.
+func (s *GCSStore) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	w := s.bucket.Object(id).NewWriter(ctx)
+	w.ContentType = info.ContentType
+
+	if _, err := io.Copy(w, reader); err != nil {
+		w.Close()
+		return fmt.Errorf("error writing object to GCS: %v", err)
+	}
+
+	return w.Close()
+}
+
+// Exists reports whether the object with the given id exists in the bucket.
+func (s *GCSStore) Exists(ctx context.Context, id string) (bool, error) {
+	_, err := s.bucket.Object(id).Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List returns the ids of objects under prefix.
+func (s *GCSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	it := s.bucket.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, attrs.Name)
+	}
+
+	return ids, nil
+}
+
+// Copy copies an object from source with a progress bar.
+func (s *GCSStore) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
+	return genericCopy(ctx, s, source, id, dryRun)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+)
+
+// OSSStore is the Aliyun Object Storage Service ObjectStore driver.
+type OSSStore struct {
+	bucket *oss.Bucket
+}
+
+// NewOSSStore connects to an Aliyun OSS bucket.
+func NewOSSStore(ctx context.Context, params *StoreParams) (*OSSStore, error) {
+	client, err := oss.New(params.Endpoint, params.Key, params.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("error creating OSS client: %v", err)
+	}
+
+	bucket, err := client.Bucket(params.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("error opening OSS bucket: %v", err)
+	}
+
+	return &OSSStore{bucket: bucket}, nil
+}
+
+// Stat returns metadata for the object with the given id.
+func (s *OSSStore) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	header, err := s.bucket.GetObjectMeta(id)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	return ObjectInfo{
+		Size:        size,
+		ETag:        header.Get("ETag"),
+		ContentType: header.Get("Content-Type"),
+	}, nil
+}
+
+// Get returns a stream for the object with the given id.
+func (s *OSSStore) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	return s.bucket.GetObject(id)
+}
+
+// Put uploads reader as the object with the given id.
+func (s *OSSStore) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	return s.bucket.PutObject(id, reader, oss.ContentType(info.ContentType))
+}
+
+// Exists reports whether the object with the given id exists in the bucket.
+func (s *OSSStore) Exists(ctx context.Context, id string) (bool, error) {
+	return s.bucket.IsObjectExist(id)
+}
+
+// List returns the ids of objects under prefix.
+func (s *OSSStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	marker := ""
+	for {
+		result, err := s.bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker))
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range result.Objects {
+			ids = append(ids, obj.Key)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	return ids, nil
+}
+
+// Copy copies an object from source with a progress bar.
+func (s *OSSStore) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
+	return genericCopy(ctx, s, source, id, dryRun)
+}
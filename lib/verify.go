@@ -0,0 +1,232 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VerifyLevel controls how thoroughly a migrated object's integrity is
+// checked against its source.
+type VerifyLevel string
+
+const (
+	VerifyNone   VerifyLevel = "none"
+	VerifySize   VerifyLevel = "size"
+	VerifyETag   VerifyLevel = "etag"
+	VerifySHA256 VerifyLevel = "sha256"
+)
+
+// ParseVerifyLevel validates a --verify flag value.
+func ParseVerifyLevel(s string) (VerifyLevel, error) {
+	switch VerifyLevel(s) {
+	case VerifyNone, VerifySize, VerifyETag, VerifySHA256:
+		return VerifyLevel(s), nil
+	default:
+		return "", fmt.Errorf("unknown verify level: %s (want none, size, etag or sha256)", s)
+	}
+}
+
+// verificationError marks a failed integrity check so withRetry treats it as
+// retryable alongside transient S3 errors.
+type verificationError struct {
+	msg string
+}
+
+func (e *verificationError) Error() string { return e.msg }
+
+// verifyObject checks that doc was migrated correctly according to level.
+func verifyObject(ctx context.Context, source, dest ObjectStore, doc Object, level VerifyLevel, partSize int64) (bool, error) {
+	if level == VerifyNone {
+		return true, nil
+	}
+
+	sourceInfo, err := source.Stat(ctx, doc.StorageID)
+	if err != nil {
+		return false, fmt.Errorf("error getting source object info: %v", err)
+	}
+
+	destInfo, err := dest.Stat(ctx, doc.StorageID)
+	if err != nil {
+		return false, fmt.Errorf("error getting destination object info: %v", err)
+	}
+
+	if sourceInfo.Size != destInfo.Size {
+		return false, nil
+	}
+
+	switch level {
+	case VerifySize:
+		return true, nil
+	case VerifyETag:
+		return etagsMatch(ctx, source, dest, doc.StorageID, sourceInfo, destInfo, partSize)
+	case VerifySHA256:
+		if doc.Sha == "" {
+			return etagsMatch(ctx, source, dest, doc.StorageID, sourceInfo, destInfo, partSize)
+		}
+
+		destSum, err := sha256Sum(ctx, dest, doc.StorageID)
+		if err != nil {
+			return false, fmt.Errorf("error computing destination sha256: %v", err)
+		}
+
+		return strings.EqualFold(destSum, doc.Sha), nil
+	default:
+		return false, fmt.Errorf("unknown verify level: %s", level)
+	}
+}
+
+// etagsMatch compares ETags directly, falling back to recomputing the
+// composite MD5-of-MD5s for multipart objects (ETags of the form "hash-N").
+func etagsMatch(ctx context.Context, source, dest ObjectStore, id string, sourceInfo, destInfo ObjectInfo, partSize int64) (bool, error) {
+	if sourceInfo.ETag != "" && sourceInfo.ETag == destInfo.ETag {
+		return true, nil
+	}
+
+	if !strings.Contains(sourceInfo.ETag, "-") && !strings.Contains(destInfo.ETag, "-") {
+		return false, nil
+	}
+
+	sourceComposite, err := compositeMD5(ctx, source, id, partSize)
+	if err != nil {
+		return false, fmt.Errorf("error computing source composite ETag: %v", err)
+	}
+
+	destComposite, err := compositeMD5(ctx, dest, id, partSize)
+	if err != nil {
+		return false, fmt.Errorf("error computing destination composite ETag: %v", err)
+	}
+
+	return sourceComposite == destComposite, nil
+}
+
+// compositeMD5 reproduces S3's multipart ETag: the MD5 of the concatenated
+// per-part MD5s, followed by "-<numParts>".
+func compositeMD5(ctx context.Context, store ObjectStore, id string, partSize int64) (string, error) {
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+
+	reader, err := store.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var digests []byte
+	numParts := 0
+	buf := make([]byte, partSize)
+
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			sum := md5.Sum(buf[:n])
+			digests = append(digests, sum[:]...)
+			numParts++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	composite := md5.Sum(digests)
+	return fmt.Sprintf("%x-%d", composite, numParts), nil
+}
+
+// sha256Sum hashes the full contents of the object with the given id.
+func sha256Sum(ctx context.Context, store ObjectStore, id string) (string, error) {
+	reader, err := store.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, reader); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// VerifyStorage is the `verify` subcommand: it walks the Mongo filter and
+// checks each object's integrity against its source without copying.
+func VerifyStorage(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	params, count, _, err := prepareMigration(ctx)
+	if err != nil || params == nil {
+		return err
+	}
+
+	if params.Verify == VerifyNone {
+		params.Verify = VerifySHA256
+	}
+
+	return verifyObjects(ctx, params, count)
+}
+
+func verifyObjects(ctx context.Context, params *S3MigrationParams, totalCount int64) error {
+	sourceClient, err := NewObjectStore(ctx, params.Source)
+	if err != nil {
+		return err
+	}
+
+	destClient, err := NewObjectStore(ctx, params.Destination)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := params.Collection.Find(ctx, params.Filter, &options.FindOptions{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var verifiedCount, verificationFailedCount, errorCount int64
+
+	for cursor.Next(ctx) {
+		var doc Object
+		if err := cursor.Decode(&doc); err != nil {
+			logger.Error("error decoding document", "err", err)
+			errorCount++
+			continue
+		}
+
+		verified, err := verifyObject(ctx, sourceClient, destClient, doc, params.Verify, params.Source.PartSize)
+		if err != nil {
+			logger.Error("error verifying object", "storage_id", doc.StorageID, "err", err)
+			errorCount++
+			continue
+		}
+
+		if !verified {
+			logger.Error("verification failed for object", "storage_id", doc.StorageID)
+			verificationFailedCount++
+			continue
+		}
+
+		verifiedCount++
+	}
+
+	fmt.Println("\nVerification Summary Report:")
+	fmt.Printf("Total Objects: %d\n", totalCount)
+	fmt.Printf("Verified: %d\n", verifiedCount)
+	fmt.Printf("Verification Failed: %d\n", verificationFailedCount)
+	fmt.Printf("Errors: %d\n", errorCount)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// memStore is a minimal in-memory ObjectStore used to exercise compositeMD5
+// without a real backend.
+type memStore struct {
+	objects map[string][]byte
+}
+
+func (s *memStore) Stat(ctx context.Context, id string) (ObjectInfo, error) {
+	return ObjectInfo{Size: int64(len(s.objects[id]))}, nil
+}
+
+func (s *memStore) Get(ctx context.Context, id string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.objects[id])), nil
+}
+
+func (s *memStore) Put(ctx context.Context, id string, reader io.Reader, size int64, info ObjectInfo) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s.objects[id] = data
+	return nil
+}
+
+func (s *memStore) Copy(ctx context.Context, source ObjectStore, id string, dryRun bool) error {
+	return genericCopy(ctx, s, source, id, dryRun)
+}
+
+func (s *memStore) Exists(ctx context.Context, id string) (bool, error) {
+	_, ok := s.objects[id]
+	return ok, nil
+}
+
+func (s *memStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var ids []string
+	for id := range s.objects {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func TestCompositeMD5ChunkBoundaries(t *testing.T) {
+	partSize := int64(4)
+	data := []byte("abcdefghij") // 10 bytes -> 3 parts of 4, 4, 2
+
+	store := &memStore{objects: map[string][]byte{"obj": data}}
+
+	got, err := compositeMD5(context.Background(), store, "obj", partSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum1 := md5.Sum(data[0:4])
+	sum2 := md5.Sum(data[4:8])
+	sum3 := md5.Sum(data[8:10])
+	digests := append(append(append([]byte{}, sum1[:]...), sum2[:]...), sum3[:]...)
+	composite := md5.Sum(digests)
+	want := fmt.Sprintf("%x-%d", composite, 3)
+
+	if got != want {
+		t.Errorf("compositeMD5() = %q, want %q", got, want)
+	}
+}
+
+func TestCompositeMD5ExactMultipleOfPartSize(t *testing.T) {
+	partSize := int64(5)
+	data := []byte("0123456789") // exactly 2 parts of 5
+
+	store := &memStore{objects: map[string][]byte{"obj": data}}
+
+	got, err := compositeMD5(context.Background(), store, "obj", partSize)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum1 := md5.Sum(data[0:5])
+	sum2 := md5.Sum(data[5:10])
+	digests := append(append([]byte{}, sum1[:]...), sum2[:]...)
+	composite := md5.Sum(digests)
+	want := fmt.Sprintf("%x-%d", composite, 2)
+
+	if got != want {
+		t.Errorf("compositeMD5() = %q, want %q", got, want)
+	}
+}
+
+func TestCompositeMD5SinglePartSmallerThanPartSize(t *testing.T) {
+	store := &memStore{objects: map[string][]byte{"obj": []byte("hi")}}
+
+	got, err := compositeMD5(context.Background(), store, "obj", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sum := md5.Sum([]byte("hi"))
+	composite := md5.Sum(sum[:])
+	want := fmt.Sprintf("%x-%d", composite, 1)
+
+	if got != want {
+		t.Errorf("compositeMD5() = %q, want %q", got, want)
+	}
+}
+
+func TestCompositeMD5DefaultsPartSize(t *testing.T) {
+	store := &memStore{objects: map[string][]byte{"obj": []byte("hello")}}
+
+	if _, err := compositeMD5(context.Background(), store, "obj", 0); err != nil {
+		t.Fatalf("unexpected error with non-positive partSize: %v", err)
+	}
+}
@@ -0,0 +1,405 @@
+/*
+Copyright © 2025 Sergio Marin <@highercomve>
+*/
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/time/rate"
+)
+
+// WatchParams configures WatchStorage's tailing behavior, on top of the
+// base S3MigrationParams shared with a one-shot migration.
+type WatchParams struct {
+	ResumeTokenFile      string
+	StartAtOperationTime time.Time
+	PollInterval         time.Duration
+}
+
+// WatchStorage is the `watch` subcommand: it tails the source collection for
+// inserted or updated documents and migrates each as it arrives, instead of
+// walking a fixed snapshot. It's meant for cutover windows where writes keep
+// landing against the source while the bulk migration runs or has finished.
+func WatchStorage(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	params, limiter, err := buildMigrationParams(ctx)
+	if err != nil {
+		return err
+	}
+
+	watchParams := &WatchParams{
+		ResumeTokenFile: viper.GetString("resume-token-file"),
+		PollInterval:    viper.GetDuration("poll-interval"),
+	}
+
+	if s := viper.GetString("start-at-operation-time"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("error parsing --start-at-operation-time: %v", err)
+		}
+		watchParams.StartAtOperationTime = t
+	}
+
+	return watchObjects(ctx, params, watchParams, limiter)
+}
+
+// watchObjects starts the worker pool and feeds it from tailChanges until
+// ctx is canceled (SIGINT/SIGTERM) or tailing fails permanently.
+func watchObjects(ctx context.Context, params *S3MigrationParams, watchParams *WatchParams, limiter *rate.Limiter) error {
+	sourceClient, err := NewObjectStore(ctx, params.Source)
+	if err != nil {
+		return err
+	}
+
+	destClient, err := NewObjectStore(ctx, params.Destination)
+	if err != nil {
+		return err
+	}
+
+	stateStore, err := newStateStore(params)
+	if err != nil {
+		return err
+	}
+	defer stateStore.Close(ctx)
+
+	maxAttempts := params.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	logger.Info("Watching for new and updated objects, press Ctrl+C to stop")
+
+	tracker := newResumeTokenTracker(watchParams.ResumeTokenFile)
+
+	var wg sync.WaitGroup
+	docs := make(chan watchItem, params.Concurrency)
+
+	for i := int64(0); i < params.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range docs {
+				// Once ctx is canceled, leave the token pending so the
+				// persisted position stays before this unprocessed event.
+				if limiter != nil {
+					if err := limiter.Wait(ctx); err != nil {
+						continue
+					}
+				}
+
+				watchCopyObject(ctx, sourceClient, destClient, stateStore, item.doc, params, maxAttempts)
+				if item.token != nil && ctx.Err() == nil {
+					tracker.complete(item.token)
+				}
+			}
+		}()
+	}
+
+	tailErr := tailChanges(ctx, params, watchParams, tracker, docs)
+	close(docs)
+	wg.Wait()
+
+	if tailErr != nil && !errors.Is(tailErr, context.Canceled) {
+		return tailErr
+	}
+
+	fmt.Println("\nWatch stopped.")
+	return nil
+}
+
+// watchCopyObject migrates a single document discovered by tailChanges,
+// mirroring migrateObjects' per-object logic: skip objects missing from the
+// source, skip ones already verified in the destination, otherwise copy with
+// retry and checkpoint the outcome.
+func watchCopyObject(ctx context.Context, sourceClient, destClient ObjectStore, stateStore StateStore, doc Object, params *S3MigrationParams, maxAttempts int) {
+	storageID := doc.StorageID
+
+	exists, err := sourceClient.Exists(ctx, storageID)
+	if err != nil {
+		logger.Error("error checking object", "storage_id", storageID, "err", err)
+		return
+	}
+	if !exists {
+		logger.Info("object not found in source bucket", "storage_id", storageID)
+		saveCheckpoint(ctx, stateStore, doc, StatusSkipped, 1)
+		return
+	}
+
+	destExists, err := destClient.Exists(ctx, storageID)
+	if err != nil {
+		logger.Error("error checking object in destination bucket", "storage_id", storageID, "err", err)
+		return
+	}
+
+	if destExists {
+		verified, verr := verifyObject(ctx, sourceClient, destClient, doc, params.Verify, params.Source.PartSize)
+		if verr != nil {
+			logger.Error("error verifying object", "storage_id", storageID, "err", verr)
+			saveCheckpoint(ctx, stateStore, doc, StatusError, 1)
+			return
+		}
+		if verified {
+			logger.Info("object already up to date in destination bucket", "storage_id", storageID)
+			saveCheckpoint(ctx, stateStore, doc, StatusSkipped, 1)
+			return
+		}
+		logger.Info("object changed since last copy, re-copying", "storage_id", storageID)
+	}
+
+	if params.DryRun {
+		logger.Info("dry run: would copy object from source to destination", "storage_id", storageID)
+		return
+	}
+
+	attempts, err := withRetry(ctx, maxAttempts, func() error {
+		if err := destClient.Copy(ctx, sourceClient, storageID, params.DryRun); err != nil {
+			return err
+		}
+
+		verified, verr := verifyObject(ctx, sourceClient, destClient, doc, params.Verify, params.Source.PartSize)
+		if verr != nil {
+			return verr
+		}
+		if !verified {
+			return &verificationError{msg: fmt.Sprintf("verification failed for object %s", storageID)}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("error copying object", "storage_id", storageID, "attempts", attempts, "err", err)
+		saveCheckpoint(ctx, stateStore, doc, StatusError, attempts)
+		return
+	}
+
+	logger.Info("copied object", "storage_id", storageID)
+	saveCheckpoint(ctx, stateStore, doc, StatusCopied, attempts)
+}
+
+// watchItem pairs a document discovered by tailChanges/pollChanges with the
+// resume token tracking handle it must report back to once a worker finishes
+// processing it, so the persisted resume token never advances past an event
+// that hasn't actually been delivered yet. token is nil for pollChanges,
+// which has no resume token to advance.
+type watchItem struct {
+	doc   Object
+	token *trackedToken
+}
+
+// tailChanges feeds docs from a MongoDB change stream. Each event is
+// registered with tracker before being handed to a worker, and the caller is
+// expected to call tracker.complete on its token once the worker finishes
+// processing it; tracker only persists a resume token once it and every
+// earlier-registered token have completed, so a crash never loses an event
+// that's still in flight in the worker pool. If change streams aren't
+// available (e.g. a standalone MongoDB without a replica set), it falls back
+// to polling on timemodified.
+func tailChanges(ctx context.Context, params *S3MigrationParams, watchParams *WatchParams, tracker *resumeTokenTracker, docs chan<- watchItem) error {
+	stream, err := openChangeStream(ctx, params, watchParams)
+	if err != nil {
+		logger.Info("change streams unavailable, falling back to polling", "err", err, "poll_interval", watchParams.PollInterval)
+		return pollChanges(ctx, params, watchParams, docs)
+	}
+	defer stream.Close(ctx)
+
+	for stream.Next(ctx) {
+		var event struct {
+			OperationType string `bson:"operationType"`
+			FullDocument  Object `bson:"fullDocument"`
+		}
+
+		if err := stream.Decode(&event); err != nil {
+			logger.Error("error decoding change event", "err", err)
+			continue
+		}
+
+		token := tracker.track(stream.ResumeToken())
+
+		select {
+		case docs <- watchItem{doc: event.FullDocument, token: token}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return stream.Err()
+}
+
+// openChangeStream opens a change stream on params.Collection restricted to
+// inserts/updates/replaces, resuming from the persisted token when one
+// exists, or from --start-at-operation-time otherwise.
+func openChangeStream(ctx context.Context, params *S3MigrationParams, watchParams *WatchParams) (*mongo.ChangeStream, error) {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	if token, err := loadResumeToken(watchParams.ResumeTokenFile); err == nil {
+		opts.SetResumeAfter(token)
+	} else if !watchParams.StartAtOperationTime.IsZero() {
+		ts := primitive.Timestamp{T: uint32(watchParams.StartAtOperationTime.Unix())}
+		opts.SetStartAtOperationTime(&ts)
+	}
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}},
+		}}},
+	}
+
+	return params.Collection.Watch(ctx, pipeline, opts)
+}
+
+// pollChanges is the fallback for deployments without change stream support:
+// it repeatedly re-queries for documents modified since the last seen
+// timemodified value.
+func pollChanges(ctx context.Context, params *S3MigrationParams, watchParams *WatchParams, docs chan<- watchItem) error {
+	interval := watchParams.PollInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	lastSeen := watchParams.StartAtOperationTime
+	if lastSeen.IsZero() {
+		lastSeen = time.Now()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		filter := bson.M{"timemodified": bson.M{"$gt": lastSeen}}
+		cursor, err := params.Collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "timemodified", Value: 1}}))
+		if err != nil {
+			logger.Error("error polling for changes", "err", err)
+		} else {
+			for cursor.Next(ctx) {
+				var doc Object
+				if err := cursor.Decode(&doc); err != nil {
+					logger.Error("error decoding polled document", "err", err)
+					continue
+				}
+
+				if doc.TimeModified.After(lastSeen) {
+					lastSeen = doc.TimeModified
+				}
+
+				select {
+				case docs <- watchItem{doc: doc}:
+				case <-ctx.Done():
+					cursor.Close(ctx)
+					return ctx.Err()
+				}
+			}
+			cursor.Close(ctx)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// loadResumeToken reads a previously persisted change stream resume token.
+func loadResumeToken(path string) (bson.Raw, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no resume token file configured")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.Raw(data), nil
+}
+
+// saveResumeToken persists the latest change stream resume token so a
+// restarted watch can continue from where it left off. It's a no-op when
+// --resume-token-file isn't set.
+func saveResumeToken(path string, token bson.Raw) error {
+	if path == "" {
+		return nil
+	}
+
+	return os.WriteFile(path, token, 0600)
+}
+
+// trackedToken is a single change event's resume token as registered with a
+// resumeTokenTracker, along with whether the worker pool has finished
+// processing the event it came from.
+type trackedToken struct {
+	token bson.Raw
+	done  bool
+}
+
+// resumeTokenTracker persists change stream resume tokens in the order their
+// events arrived, not the order their (concurrent) workers finish processing
+// them. Without this, saving a token as soon as it's decoded can advance the
+// persisted position past an event that's still sitting in the docs channel
+// or being retried in a worker, so a crash before that worker finishes would
+// silently drop it on restart. track registers an in-flight event; complete
+// marks it done and, if that closes a contiguous run at the front of the
+// queue, advances the persisted token to the last one in that run.
+type resumeTokenTracker struct {
+	mu      sync.Mutex
+	path    string
+	pending []*trackedToken
+}
+
+// newResumeTokenTracker creates a tracker that persists to path (a no-op
+// path, same as saveResumeToken, disables persistence).
+func newResumeTokenTracker(path string) *resumeTokenTracker {
+	return &resumeTokenTracker{path: path}
+}
+
+// track registers token as belonging to an event that's about to be handed
+// to a worker, returning the handle to pass to complete once it's done.
+func (t *resumeTokenTracker) track(token bson.Raw) *trackedToken {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tt := &trackedToken{token: token}
+	t.pending = append(t.pending, tt)
+	return tt
+}
+
+// complete marks tt as finished and persists the resume token up to the
+// furthest point reachable through a contiguous run of finished tokens at
+// the front of the queue, so it never advances past one still in flight.
+func (t *resumeTokenTracker) complete(tt *trackedToken) {
+	t.mu.Lock()
+	tt.done = true
+
+	var advanceTo bson.Raw
+	i := 0
+	for ; i < len(t.pending) && t.pending[i].done; i++ {
+		advanceTo = t.pending[i].token
+	}
+	t.pending = t.pending[i:]
+	t.mu.Unlock()
+
+	if advanceTo == nil {
+		return
+	}
+
+	if err := saveResumeToken(t.path, advanceTo); err != nil {
+		logger.Error("error saving resume token", "err", err)
+	}
+}